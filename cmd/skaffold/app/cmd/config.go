@@ -0,0 +1,43 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+)
+
+// NewCmdConfig describes the `skaffold config` command group, which holds subcommands that
+// inspect a skaffold.yaml's `requires` configuration rather than building or deploying it.
+func NewCmdConfig(out io.Writer) *cobra.Command {
+	opts := config.SkaffoldOptions{Command: "config"}
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View and validate Skaffold configs",
+	}
+	cmd.PersistentFlags().StringVarP(&opts.ConfigurationFile, "filename", "f", "skaffold.yaml", "Path to the pipeline file")
+	cmd.PersistentFlags().StringSliceVar(&opts.ConfigurationFilter, "module", nil, "Restrict the resolved configuration to the named modules and what they require")
+	cmd.PersistentFlags().StringSliceVar(&opts.Profiles, "profile", nil, "Activate profiles by name")
+	cmd.PersistentFlags().BoolVar(&opts.AllowCyclicConfigs, "allow-cyclic-configs", false, "Allow cycles in the resolved `requires` dependency graph instead of failing")
+
+	cmd.AddCommand(NewCmdConfigGraph(out, &opts))
+	return cmd
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+)
+
+var graphFormat string
+
+// NewCmdConfigGraph describes the `skaffold config graph` command, which emits the `requires`
+// dependency DAG that `getAllConfigs` resolved for the current skaffold.yaml, so users can
+// visualize it instead of reverse-engineering it from error messages. opts is shared with the
+// parent `skaffold config` command, so flags like --filename and --allow-cyclic-configs bound
+// there are visible here too.
+func NewCmdConfigGraph(out io.Writer, opts *config.SkaffoldOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Print the resolved `requires` dependency graph",
+		Long:  "Resolves the dependency graph declared by `requires` in the skaffold.yaml configured by --filename and prints it as DOT or JSON, including which profiles were activated on each edge.",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runConfigGraph(out, *opts)
+		},
+	}
+	cmd.Flags().StringVar(&graphFormat, "format", "dot", "output format, one of 'dot' or 'json'")
+	return cmd
+}
+
+func runConfigGraph(out io.Writer, opts config.SkaffoldOptions) error {
+	r, err := newConfigResolver(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	graph, err := r.buildDependencyGraph(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch graphFormat {
+	case "dot":
+		fmt.Fprintln(out, graph.renderDOT())
+	case "json":
+		b, err := graph.renderJSON()
+		if err != nil {
+			return fmt.Errorf("rendering graph as json: %w", err)
+		}
+		fmt.Fprintln(out, string(b))
+	default:
+		return fmt.Errorf("unknown --format %q, must be one of 'dot' or 'json'", graphFormat)
+	}
+	return nil
+}
+
+// renderDOT renders the graph in Graphviz DOT syntax, with edges labeled by the profiles they
+// activate, if any.
+func (g *depGraph) renderDOT() string {
+	var b strings.Builder
+	b.WriteString("digraph requires {\n")
+	for _, key := range g.sortedNodeKeys() {
+		n := g.nodes[key]
+		fmt.Fprintf(&b, "  %q [label=%q];\n", key, n.String())
+	}
+	for _, from := range g.sortedNodeKeys() {
+		for _, to := range g.edges[from] {
+			if profiles := g.profiles[from+"|"+to]; len(profiles) > 0 {
+				fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", from, to, strings.Join(profiles, ","))
+			} else {
+				fmt.Fprintf(&b, "  %q -> %q;\n", from, to)
+			}
+		}
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// graphJSON is the JSON-serializable shape of a depGraph.
+type graphJSON struct {
+	Nodes []nodeJSON `json:"nodes"`
+	Edges []edgeJSON `json:"edges"`
+}
+
+type nodeJSON struct {
+	Name    string `json:"name"`
+	DocPath string `json:"docPath"`
+}
+
+type edgeJSON struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Profiles []string `json:"profiles,omitempty"`
+}
+
+func (g *depGraph) renderJSON() ([]byte, error) {
+	out := graphJSON{}
+	for _, key := range g.sortedNodeKeys() {
+		n := g.nodes[key]
+		out.Nodes = append(out.Nodes, nodeJSON{Name: n.name, DocPath: n.docPath})
+	}
+	for _, from := range g.sortedNodeKeys() {
+		for _, to := range g.edges[from] {
+			out.Edges = append(out.Edges, edgeJSON{From: from, To: to, Profiles: g.profiles[from+"|"+to]})
+		}
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+func (g *depGraph) sortedNodeKeys() []string {
+	keys := make([]string, 0, len(g.nodes))
+	for key := range g.nodes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func buildTestGraph() *depGraph {
+	graph := newDepGraph()
+	cfg00 := graphNode{docPath: "skaffold.yaml", name: "cfg00"}
+	cfg10 := graphNode{docPath: "doc1/skaffold.yaml", name: "cfg10"}
+	graph.addEdge(cfg00, cfg10, []string{"pf0"})
+	return graph
+}
+
+func TestRenderDOT(t *testing.T) {
+	graph := buildTestGraph()
+
+	expected := `digraph requires {
+  "doc1/skaffold.yaml|cfg10" [label="cfg10 @ doc1/skaffold.yaml"];
+  "skaffold.yaml|cfg00" [label="cfg00 @ skaffold.yaml"];
+  "skaffold.yaml|cfg00" -> "doc1/skaffold.yaml|cfg10" [label="pf0"];
+}`
+	if got := graph.renderDOT(); got != expected {
+		t.Errorf("renderDOT() mismatch:\ngot:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	graph := buildTestGraph()
+
+	b, err := graph.renderJSON()
+	if err != nil {
+		t.Fatalf("renderJSON: %v", err)
+	}
+
+	expected := `{
+  "nodes": [
+    {
+      "name": "cfg10",
+      "docPath": "doc1/skaffold.yaml"
+    },
+    {
+      "name": "cfg00",
+      "docPath": "skaffold.yaml"
+    }
+  ],
+  "edges": [
+    {
+      "from": "skaffold.yaml|cfg00",
+      "to": "doc1/skaffold.yaml|cfg10",
+      "profiles": [
+        "pf0"
+      ]
+    }
+  ]
+}`
+	if got := string(b); got != expected {
+		t.Errorf("renderJSON() mismatch:\ngot:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestRunConfigGraph(t *testing.T) {
+	tests := []struct {
+		description string
+		format      string
+		wantErr     string
+		wantOutput  string
+	}{
+		{
+			description: "dot format",
+			format:      "dot",
+			wantOutput:  "digraph requires {",
+		},
+		{
+			description: "json format",
+			format:      "json",
+			wantOutput:  `"name": "cfg00"`,
+		},
+		{
+			description: "unknown format",
+			format:      "yaml",
+			wantErr:     `unknown --format "yaml", must be one of 'dot' or 'json'`,
+		},
+	}
+
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir()
+			tmpDir.Write("skaffold.yaml", fmt.Sprintf(template, "cfg00", "", "00", "00", "00"))
+			tmpDir.Chdir()
+
+			graphFormat = test.format
+			var out bytes.Buffer
+			err := runConfigGraph(&out, config.SkaffoldOptions{
+				Command:           "config",
+				ConfigurationFile: "skaffold.yaml",
+			})
+
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			t.CheckNoError(err)
+			if !strings.Contains(out.String(), test.wantOutput) {
+				t.Errorf("runConfigGraph() output = %q, want it to contain %q", out.String(), test.wantOutput)
+			}
+		})
+	}
+}
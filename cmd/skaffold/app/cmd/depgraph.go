@@ -0,0 +1,315 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// graphNode identifies a single config within the `requires` graph: one config can only be
+// identified uniquely by the document it lives in together with its name, since sibling documents
+// are free to reuse config names.
+type graphNode struct {
+	docPath string
+	name    string
+}
+
+func (n graphNode) key() string {
+	return n.docPath + "|" + n.name
+}
+
+func (n graphNode) String() string {
+	return fmt.Sprintf("%s @ %s", n.name, n.docPath)
+}
+
+// depGraph is the directed graph of `requires` edges discovered while resolving a primary
+// skaffold.yaml: an edge from A to B means A requires B.
+type depGraph struct {
+	nodes    map[string]graphNode
+	edges    map[string][]string // node key -> keys of the nodes it requires, in declaration order
+	profiles map[string][]string // "from|to" -> profiles activated on that edge, for `skaffold config graph`
+}
+
+func newDepGraph() *depGraph {
+	return &depGraph{
+		nodes:    make(map[string]graphNode),
+		edges:    make(map[string][]string),
+		profiles: make(map[string][]string),
+	}
+}
+
+func (g *depGraph) addNode(n graphNode) {
+	g.nodes[n.key()] = n
+}
+
+// addEdge records a `requires` edge from "from" to "to", activating the given profiles (if any)
+// in "to".
+func (g *depGraph) addEdge(from, to graphNode, activatedProfiles []string) {
+	g.addNode(from)
+	g.addNode(to)
+	g.edges[from.key()] = append(g.edges[from.key()], to.key())
+	if len(activatedProfiles) > 0 {
+		g.profiles[from.key()+"|"+to.key()] = activatedProfiles
+	}
+}
+
+// buildDependencyGraph walks the same `requires` traversal as resolve, but only to record the
+// graph of edges between configs, so that cycles can be detected before any config is actually
+// resolved. It reuses loadDocument/resolveDependencyDoc, so remote sources are only fetched once
+// whether or not cycle detection is enabled.
+func (r *configResolver) buildDependencyGraph(ctx context.Context) (*depGraph, error) {
+	graph := newDepGraph()
+	visited := make(map[string]bool)
+
+	if len(r.opts.ConfigurationFilter) == 0 {
+		if err := r.visitGraph(ctx, r.opts.ConfigurationFile, nil, r.opts.Profiles, graph, visited); err != nil {
+			return nil, err
+		}
+		return graph, nil
+	}
+
+	for _, name := range r.opts.ConfigurationFilter {
+		docPath, found, err := r.findAnywhere(ctx, r.opts.ConfigurationFile, name)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			// findAnywhere's failure is surfaced identically by the real resolution pass below;
+			// don't duplicate the "not found" error here.
+			return graph, nil
+		}
+		if err := r.visitGraph(ctx, docPath, []string{name}, r.opts.Profiles, graph, visited); err != nil {
+			return nil, err
+		}
+	}
+	return graph, nil
+}
+
+// visitGraph records, for every config reachable from docPath (restricted to `names`, if given),
+// a node plus one edge per `requires` entry, tagged with the profiles that edge activates.
+// `visited` only guards against re-descending into a node already expanded; edges into an
+// already-visited node are still recorded, which is what lets cycles show up as graph edges
+// instead of being silently dropped.
+func (r *configResolver) visitGraph(ctx context.Context, docPath string, names, profiles []string, graph *depGraph, visited map[string]bool) error {
+	entries, err := r.loadDocument(ctx, docPath)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if len(names) > 0 && !contains(names, e.name) {
+			continue
+		}
+
+		from := graphNode{docPath: e.docPath, name: e.name}
+		graph.addNode(from)
+
+		key := from.key()
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+
+		for _, dep := range e.config.Dependencies {
+			depDocPath, err := r.resolveDependencyDoc(ctx, e.docPath, dep)
+			if err != nil {
+				return err
+			}
+
+			depEntries, err := r.loadDocument(ctx, depDocPath)
+			if err != nil {
+				return err
+			}
+			depProfiles := activatedProfiles(dep.ActiveProfiles, profiles)
+			for _, de := range depEntries {
+				if len(dep.Names) > 0 && !contains(dep.Names, de.name) {
+					continue
+				}
+				graph.addEdge(from, graphNode{docPath: de.docPath, name: de.name}, depProfiles)
+			}
+
+			if err := r.visitGraph(ctx, depDocPath, dep.Names, depProfiles, graph, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findCycles returns every non-trivial strongly connected component of the graph, computed with
+// Tarjan's algorithm: a component is non-trivial if it contains more than one node, or a single
+// node with a self-loop. Each returned cycle is a simple path through the component's edges that
+// starts and ends on the same node.
+func (g *depGraph) findCycles() [][]graphNode {
+	t := &tarjan{
+		graph:   g,
+		index:   make(map[string]int),
+		low:     make(map[string]int),
+		onStack: make(map[string]bool),
+	}
+	// Iterate in a stable order so that, for a graph with more than one cycle, the order of the
+	// cycles in the result (and hence of CyclicConfigsError's message) is deterministic.
+	for _, key := range g.sortedNodeKeys() {
+		if _, ok := t.index[key]; !ok {
+			t.strongConnect(key)
+		}
+	}
+
+	var cycles [][]graphNode
+	for _, scc := range t.sccs {
+		if len(scc) == 1 && !g.hasEdge(scc[0], scc[0]) {
+			continue // a lone node with no self-loop is not a cycle.
+		}
+		cycles = append(cycles, g.cyclePath(scc))
+	}
+	return cycles
+}
+
+func (g *depGraph) hasEdge(from, to string) bool {
+	for _, e := range g.edges[from] {
+		if e == to {
+			return true
+		}
+	}
+	return false
+}
+
+// cyclePath walks the edges restricted to the given strongly connected component, starting from
+// its first member, until it revisits that starting node, backing up out of dead ends, producing a
+// simple cycle through the component suitable for display.
+func (g *depGraph) cyclePath(scc []string) []graphNode {
+	if len(scc) == 1 {
+		// A single-node component only qualifies as a cycle via a self-loop, so the path is trivial.
+		return []graphNode{g.nodes[scc[0]], g.nodes[scc[0]]}
+	}
+
+	inSCC := make(map[string]bool, len(scc))
+	for _, key := range scc {
+		inSCC[key] = true
+	}
+
+	start := scc[0]
+	path := []string{start}
+	seen := map[string]bool{start: true}
+
+	cur := start
+	for {
+		var next string
+		for _, e := range g.edges[cur] {
+			if e == start && len(path) > 1 {
+				next = e
+				break
+			}
+			if inSCC[e] && !seen[e] {
+				next = e
+				break
+			}
+		}
+		if next == "" {
+			// Dead end: every successor of cur in the component has already been visited. Back up
+			// to the previous node and try its remaining successors; since the component is
+			// strongly connected, some path back to start is always found.
+			path = path[:len(path)-1]
+			cur = path[len(path)-1]
+			continue
+		}
+		path = append(path, next)
+		if next == start {
+			break
+		}
+		seen[next] = true
+		cur = next
+	}
+
+	nodes := make([]graphNode, len(path))
+	for i, key := range path {
+		nodes[i] = g.nodes[key]
+	}
+	return nodes
+}
+
+// tarjan implements Tarjan's strongly connected components algorithm over a depGraph.
+type tarjan struct {
+	graph   *depGraph
+	index   map[string]int
+	low     map[string]int
+	onStack map[string]bool
+	stack   []string
+	counter int
+	sccs    [][]string
+}
+
+func (t *tarjan) strongConnect(v string) {
+	t.index[v] = t.counter
+	t.low[v] = t.counter
+	t.counter++
+	t.stack = append(t.stack, v)
+	t.onStack[v] = true
+
+	for _, w := range t.graph.edges[v] {
+		if _, ok := t.index[w]; !ok {
+			t.strongConnect(w)
+			if t.low[w] < t.low[v] {
+				t.low[v] = t.low[w]
+			}
+		} else if t.onStack[w] {
+			if t.index[w] < t.low[v] {
+				t.low[v] = t.index[w]
+			}
+		}
+	}
+
+	if t.low[v] == t.index[v] {
+		var scc []string
+		for {
+			n := len(t.stack) - 1
+			w := t.stack[n]
+			t.stack = t.stack[:n]
+			t.onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		t.sccs = append(t.sccs, scc)
+	}
+}
+
+// CyclicConfigsError is returned by getAllConfigs when the `requires` graph contains one or more
+// cycles and opts.AllowCyclicConfigs is false. It lists every cycle found, so users fixing one
+// don't have to re-run skaffold to discover the next.
+type CyclicConfigsError struct {
+	Cycles [][]graphNode
+}
+
+func (e *CyclicConfigsError) Error() string {
+	var b strings.Builder
+	b.WriteString("cyclic config dependencies detected; pass --allow-cyclic-configs to resolve anyway:\n")
+	for i, cycle := range e.Cycles {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		parts := make([]string, len(cycle))
+		for j, n := range cycle {
+			parts[j] = n.String()
+		}
+		b.WriteString(strings.Join(parts, " -> "))
+	}
+	return b.String()
+}
@@ -0,0 +1,419 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/defaults"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/transform"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+)
+
+const defaultConfigPath = "skaffold.yaml"
+
+// configEntry is a single `Config` document parsed out of a skaffold.yaml, tagged with the
+// (possibly multi-document) file it came from.
+type configEntry struct {
+	docPath string
+	docDir  string // absolute directory containing docPath; empty for the root document
+	name    string
+	config  *latest.SkaffoldConfig
+}
+
+// configResolver walks the `requires` graph of a primary skaffold.yaml, recursively resolving
+// local, git and OCI dependencies into a flat, build-order list of *latest.SkaffoldConfig.
+type configResolver struct {
+	opts    config.SkaffoldOptions
+	cache   config.DependencyCache
+	rootDir string // absolute working directory at the time the resolver was created
+
+	documents map[string][]configEntry // docPath -> parsed configs, to avoid re-reading/re-fetching
+	visited   map[string]bool          // "docPath|name" -> already resolved into the output
+}
+
+// newConfigResolver builds a configResolver for opts, falling back to a FileCache rooted in the
+// user's temp directory when opts.Cache isn't set. Shared by getAllConfigs and
+// `skaffold config graph`, which both need to walk the same `requires` graph.
+func newConfigResolver(opts config.SkaffoldOptions) (*configResolver, error) {
+	wd, err := util.RealWorkDir()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+
+	cache := opts.Cache
+	if cache == nil {
+		cache, err = config.NewFileCache(filepath.Join(os.TempDir(), "skaffold", "requires-cache"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &configResolver{
+		opts:      opts,
+		cache:     cache,
+		rootDir:   wd,
+		documents: make(map[string][]configEntry),
+		visited:   make(map[string]bool),
+	}, nil
+}
+
+// getAllConfigs reads the primary skaffold.yaml named by opts.ConfigurationFile and resolves it,
+// along with any configs it `requires`, into a single ordered list ready for the build/deploy
+// pipeline. Dependencies are returned before their dependents.
+func getAllConfigs(opts config.SkaffoldOptions) ([]*latest.SkaffoldConfig, error) {
+	r, err := newConfigResolver(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	if !opts.AllowCyclicConfigs {
+		graph, err := r.buildDependencyGraph(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if cycles := graph.findCycles(); len(cycles) > 0 {
+			return nil, &CyclicConfigsError{Cycles: cycles}
+		}
+	}
+
+	var out []*latest.SkaffoldConfig
+
+	if len(opts.ConfigurationFilter) == 0 {
+		if err := r.resolve(ctx, opts.ConfigurationFile, nil, opts.Profiles, &out); err != nil {
+			return nil, err
+		}
+		return applyTransformers(ctx, opts, out)
+	}
+
+	for _, name := range opts.ConfigurationFilter {
+		docPath, found, err := r.findAnywhere(ctx, opts.ConfigurationFile, name)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("did not find any configs matching selection %v", opts.ConfigurationFilter)
+		}
+		if err := r.resolve(ctx, docPath, []string{name}, opts.Profiles, &out); err != nil {
+			return nil, err
+		}
+	}
+	return applyTransformers(ctx, opts, out)
+}
+
+// applyTransformers runs opts.Transformers (the globally configured transformers, first) followed
+// by each resolved config's own `transformers:` stanza, in the order the configs appear in out,
+// against the full resolved config list. Transformers run last, after `requires` resolution and
+// --module/--profile have already produced the final config list, so a transformer only sees (and
+// is only contributed by) configs that survived filtering.
+func applyTransformers(ctx context.Context, opts config.SkaffoldOptions, out []*latest.SkaffoldConfig) ([]*latest.SkaffoldConfig, error) {
+	specs := transformerSpecs(opts, out)
+	if len(specs) == 0 {
+		return out, nil
+	}
+	return transform.Run(ctx, transform.FromSpecs(specs), out)
+}
+
+// transformerSpecs collects every latest.Transformer that applies to the resolved config list out:
+// opts.Transformers first, then each config's own `transformers:` stanza, in the order the configs
+// appear in out.
+func transformerSpecs(opts config.SkaffoldOptions, out []*latest.SkaffoldConfig) []latest.Transformer {
+	specs := append([]latest.Transformer{}, opts.Transformers...)
+	for _, cfg := range out {
+		specs = append(specs, cfg.Transformers...)
+	}
+	return specs
+}
+
+// resolve appends, in dependency order, the configs named by `names` (or all configs, if `names`
+// is empty) found in the document at `docPath`, recursively resolving their own `requires` first.
+// `profiles` is the set of profile names active for these configs.
+func (r *configResolver) resolve(ctx context.Context, docPath string, names []string, profiles []string, out *[]*latest.SkaffoldConfig) error {
+	entries, err := r.loadDocument(ctx, docPath)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if len(names) > 0 && !contains(names, e.name) {
+			continue
+		}
+
+		key := e.docPath + "|" + e.name
+		if r.visited[key] {
+			continue
+		}
+		r.visited[key] = true
+
+		for _, dep := range e.config.Dependencies {
+			depDocPath, err := r.resolveDependencyDoc(ctx, e.docPath, dep)
+			if err != nil {
+				return err
+			}
+			depProfiles := activatedProfiles(dep.ActiveProfiles, profiles)
+			if err := r.resolve(ctx, depDocPath, dep.Names, depProfiles, out); err != nil {
+				return err
+			}
+		}
+
+		resolved := applyProfiles(e.config, profiles)
+		if e.docDir != "" {
+			localizeToDocDir(resolved, e.docDir)
+		}
+		*out = append(*out, resolved)
+	}
+	return nil
+}
+
+// localizeToDocDir rewrites cfg's artifact workspaces, outgoing local `requires` paths, and
+// `transformers:` configPaths to be absolute, anchored at docDir. Dependency documents other than
+// the root one are parsed and resolved relative to their own directory, so once everything is
+// flattened into a single list their relative paths must be made absolute to stay meaningful to
+// the rest of the pipeline.
+func localizeToDocDir(cfg *latest.SkaffoldConfig, docDir string) {
+	if len(cfg.Build.Artifacts) > 0 {
+		artifacts := make([]*latest.Artifact, len(cfg.Build.Artifacts))
+		for i, a := range cfg.Build.Artifacts {
+			localized := *a
+			localized.Workspace = filepath.Join(docDir, a.Workspace)
+			artifacts[i] = &localized
+		}
+		cfg.Build.Artifacts = artifacts
+	}
+
+	if len(cfg.Dependencies) > 0 {
+		deps := make([]latest.ConfigDependency, len(cfg.Dependencies))
+		for i, dep := range cfg.Dependencies {
+			if dep.Path != "" {
+				dep.Path = filepath.Join(docDir, dep.Path)
+			}
+			deps[i] = dep
+		}
+		cfg.Dependencies = deps
+	}
+
+	if len(cfg.Transformers) > 0 {
+		transformers := make([]latest.Transformer, len(cfg.Transformers))
+		for i, tr := range cfg.Transformers {
+			if tr.ConfigPath != "" {
+				tr.ConfigPath = filepath.Join(docDir, tr.ConfigPath)
+			}
+			transformers[i] = tr
+		}
+		cfg.Transformers = transformers
+	}
+}
+
+// findAnywhere searches the transitive `requires` graph rooted at docPath for a config named
+// `name`, returning the document it lives in.
+func (r *configResolver) findAnywhere(ctx context.Context, docPath, name string) (string, bool, error) {
+	seen := map[string]bool{}
+	queue := []string{docPath}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		entries, err := r.loadDocument(ctx, p)
+		if err != nil {
+			return "", false, err
+		}
+		for _, e := range entries {
+			if e.name == name {
+				return e.docPath, true, nil
+			}
+			for _, dep := range e.config.Dependencies {
+				depDocPath, err := r.resolveDependencyDoc(ctx, e.docPath, dep)
+				if err != nil {
+					return "", false, err
+				}
+				queue = append(queue, depDocPath)
+			}
+		}
+	}
+	return "", false, nil
+}
+
+// resolveDependencyDoc returns the skaffold.yaml document path that `dep`, declared inside the
+// document at `callerDocPath`, refers to, fetching it first if it names a remote source.
+func (r *configResolver) resolveDependencyDoc(ctx context.Context, callerDocPath string, dep latest.ConfigDependency) (string, error) {
+	if err := validateDependencySource(dep); err != nil {
+		return "", err
+	}
+
+	switch {
+	case dep.GitRepo != nil:
+		dir, err := r.cache.Fetch(ctx, config.RemoteSource{Git: dep.GitRepo})
+		if err != nil {
+			return "", fmt.Errorf("fetching git dependency %s: %w", dep.GitRepo.Repo, err)
+		}
+		return filepath.Join(dir, configPathOrDefault(dep.GitRepo.Path)), nil
+
+	case dep.OCIRepo != nil:
+		dir, err := r.cache.Fetch(ctx, config.RemoteSource{OCI: dep.OCIRepo})
+		if err != nil {
+			return "", fmt.Errorf("fetching oci dependency %s: %w", dep.OCIRepo.Image, err)
+		}
+		return filepath.Join(dir, configPathOrDefault(dep.OCIRepo.Path)), nil
+
+	case dep.Path == "":
+		// A dependency on a config within the same document.
+		return callerDocPath, nil
+
+	default:
+		return filepath.Join(filepath.Dir(callerDocPath), dep.Path, defaultConfigPath), nil
+	}
+}
+
+// validateDependencySource rejects a ConfigDependency that sets more than one of Path, GitRepo, or
+// OCIRepo, since those three are documented as mutually exclusive ways of locating the dependency
+// and resolveDependencyDoc otherwise silently prefers GitRepo over OCIRepo over Path.
+func validateDependencySource(dep latest.ConfigDependency) error {
+	set := 0
+	if dep.Path != "" {
+		set++
+	}
+	if dep.GitRepo != nil {
+		set++
+	}
+	if dep.OCIRepo != nil {
+		set++
+	}
+	if set > 1 {
+		return fmt.Errorf("requires entry for %v sets more than one of path, git, and oci, which are mutually exclusive", dep.Names)
+	}
+	return nil
+}
+
+func configPathOrDefault(path string) string {
+	if path == "" {
+		return defaultConfigPath
+	}
+	return path
+}
+
+// loadDocument parses every `Config` document out of the skaffold.yaml at docPath, caching the
+// result. Traversal (resolving `requires`) always uses the paths as written in the document; only
+// once a config is appended to the final resolved list are its paths localized, see
+// localizeToDocDir.
+func (r *configResolver) loadDocument(ctx context.Context, docPath string) ([]configEntry, error) {
+	if entries, ok := r.documents[docPath]; ok {
+		return entries, nil
+	}
+
+	raw, err := ioutil.ReadFile(docPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", docPath, err)
+	}
+
+	var docDir string
+	if docPath != r.opts.ConfigurationFile {
+		docDir, err = filepath.Abs(filepath.Dir(docPath))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []configEntry
+	for _, doc := range strings.Split(string(raw), "\n---\n") {
+		doc = strings.TrimSpace(doc)
+		if doc == "" {
+			continue
+		}
+
+		cfg := &latest.SkaffoldConfig{}
+		if err := yaml.Unmarshal([]byte(doc), cfg); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", docPath, err)
+		}
+		if err := defaults.Set(cfg); err != nil {
+			return nil, fmt.Errorf("setting defaults for %s: %w", docPath, err)
+		}
+
+		entries = append(entries, configEntry{docPath: docPath, docDir: docDir, name: cfg.Metadata.Name, config: cfg})
+	}
+
+	r.documents[docPath] = entries
+	return entries, nil
+}
+
+// activatedProfiles computes the set of profile names active for a `requires` dependency given
+// the profiles active in the config that requires it. A dependency's ActiveProfiles entry
+// activates unconditionally when ActivatedBy is empty, and otherwise only when one of the parent's
+// active profiles appears in ActivatedBy.
+func activatedProfiles(activeProfiles []latest.ProfileDependency, parentProfiles []string) []string {
+	var activated []string
+	for _, ap := range activeProfiles {
+		if len(ap.ActivatedBy) == 0 {
+			activated = append(activated, ap.Name)
+			continue
+		}
+		for _, trigger := range ap.ActivatedBy {
+			if contains(parentProfiles, trigger) {
+				activated = append(activated, ap.Name)
+				break
+			}
+		}
+	}
+	return activated
+}
+
+// applyProfiles returns a copy of cfg with any activated profile's Build/Deploy overrides merged
+// in, leaving cfg itself untouched.
+func applyProfiles(cfg *latest.SkaffoldConfig, activeProfiles []string) *latest.SkaffoldConfig {
+	merged := *cfg
+	for _, p := range cfg.Profiles {
+		if !contains(activeProfiles, p.Name) {
+			continue
+		}
+		if p.Build.Artifacts != nil {
+			merged.Build.Artifacts = p.Build.Artifacts
+		}
+		if p.Build.TagPolicy.GitTagger != nil {
+			merged.Build.TagPolicy = p.Build.TagPolicy
+		}
+		if p.Deploy.Logs.Prefix != "" {
+			merged.Deploy.Logs = p.Deploy.Logs
+		}
+	}
+	// Once applied, profiles no longer matter on the resolved config.
+	merged.Profiles = nil
+	return &merged
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
@@ -89,6 +90,7 @@ func TestGetAllConfigs(t *testing.T) {
 		documents    []document
 		configFilter []string
 		profiles     []string
+		allowCyclic  bool
 		err          error
 		expected     []*latest.SkaffoldConfig
 	}{
@@ -171,7 +173,8 @@ requires:
 			},
 		},
 		{
-			description: "looped dependencies",
+			description: "looped dependencies, --allow-cyclic-configs",
+			allowCyclic: true,
 			documents: []document{
 				{path: "skaffold.yaml", configs: []mockCfg{{name: "cfg00", requiresStanza: `
 requires:
@@ -364,6 +367,7 @@ requires:
 				ConfigurationFile:   test.documents[0].path,
 				ConfigurationFilter: test.configFilter,
 				Profiles:            test.profiles,
+				AllowCyclicConfigs:  test.allowCyclic,
 			})
 
 			t.CheckDeepEqual(test.err, err, cmp.Comparer(errorsComparer))
@@ -372,6 +376,305 @@ requires:
 	}
 }
 
+// fakeCache is an in-memory config.DependencyCache used to exercise remote `requires` resolution
+// without hitting the network: it serves pre-written directories keyed by config.RemoteSource.Key().
+type fakeCache struct {
+	dirs  map[string]string
+	calls []string
+}
+
+func (f *fakeCache) Fetch(_ context.Context, source config.RemoteSource) (string, error) {
+	key := source.Key()
+	f.calls = append(f.calls, key)
+	dir, ok := f.dirs[key]
+	if !ok {
+		return "", fmt.Errorf("no fake source registered for key %s", key)
+	}
+	return dir, nil
+}
+
+func TestGetAllConfigsRemoteSources(t *testing.T) {
+	tests := []struct {
+		description string
+		setup       func(tmpDir *testutil.TempDir) (config.SkaffoldOptions, []*latest.SkaffoldConfig)
+	}{
+		{
+			description: "git dependency with its own nested requires",
+			setup: func(tmpDir *testutil.TempDir) (config.SkaffoldOptions, []*latest.SkaffoldConfig) {
+				tmpDir.Write("skaffold.yaml", fmt.Sprintf(template, "cfg00", `
+requires:
+  - git:
+      repo: https://example.com/remote.git
+      ref: v1
+    configs: [remoteCfg]
+`, "00", "00", "00"))
+				tmpDir.Write("vendor/remote/skaffold.yaml", strings.Join([]string{
+					fmt.Sprintf(template, "remoteCfg", `
+requires:
+  - path: ../nested
+    configs: [nestedCfg]
+`, "10", "10", "10"),
+				}, "\n---\n"))
+				tmpDir.Write("vendor/nested/skaffold.yaml", fmt.Sprintf(template, "nestedCfg", "", "20", "20", "20"))
+				tmpDir.Chdir()
+
+				wd, _ := util.RealWorkDir()
+				remoteDir := filepath.Join(wd, "vendor/remote")
+				nestedDir := filepath.Join(wd, "vendor/nested")
+
+				cache := &fakeCache{dirs: map[string]string{
+					(config.RemoteSource{Git: &latest.GitInfo{Repo: "https://example.com/remote.git", Ref: "v1"}}).Key(): remoteDir,
+				}}
+
+				expected := []*latest.SkaffoldConfig{
+					createCfg("nestedCfg", "image20", nestedDir, nil),
+					createCfg("remoteCfg", "image10", remoteDir, []latest.ConfigDependency{{Path: filepath.Join(nestedDir), Names: []string{"nestedCfg"}}}),
+					createCfg("cfg00", "image00", ".", []latest.ConfigDependency{{GitRepo: &latest.GitInfo{Repo: "https://example.com/remote.git", Ref: "v1"}, Names: []string{"remoteCfg"}}}),
+				}
+
+				return config.SkaffoldOptions{
+					Command:           "dev",
+					ConfigurationFile: "skaffold.yaml",
+					Cache:             cache,
+				}, expected
+			},
+		},
+		{
+			description: "oci dependency",
+			setup: func(tmpDir *testutil.TempDir) (config.SkaffoldOptions, []*latest.SkaffoldConfig) {
+				tmpDir.Write("skaffold.yaml", fmt.Sprintf(template, "cfg00", `
+requires:
+  - oci:
+      image: registry.example.com/configs:v1
+    configs: [remoteCfg]
+`, "00", "00", "00"))
+				tmpDir.Write("vendor/remote/skaffold.yaml", fmt.Sprintf(template, "remoteCfg", "", "10", "10", "10"))
+				tmpDir.Chdir()
+
+				wd, _ := util.RealWorkDir()
+				remoteDir := filepath.Join(wd, "vendor/remote")
+
+				cache := &fakeCache{dirs: map[string]string{
+					(config.RemoteSource{OCI: &latest.OCIInfo{Image: "registry.example.com/configs:v1"}}).Key(): remoteDir,
+				}}
+
+				expected := []*latest.SkaffoldConfig{
+					createCfg("remoteCfg", "image10", remoteDir, nil),
+					createCfg("cfg00", "image00", ".", []latest.ConfigDependency{{OCIRepo: &latest.OCIInfo{Image: "registry.example.com/configs:v1"}, Names: []string{"remoteCfg"}}}),
+				}
+
+				return config.SkaffoldOptions{
+					Command:           "dev",
+					ConfigurationFile: "skaffold.yaml",
+					Cache:             cache,
+				}, expected
+			},
+		},
+	}
+
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir()
+			opts, expected := test.setup(tmpDir)
+
+			cfgs, err := getAllConfigs(opts)
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(expected, cfgs)
+		})
+	}
+}
+
+// TestRemoteSourceKey checks that two configs requiring the same git repository at different refs
+// are cached under distinct keys, so they don't clobber each other on disk.
+func TestRemoteSourceKey(t *testing.T) {
+	a := config.RemoteSource{Git: &latest.GitInfo{Repo: "https://example.com/remote.git", Ref: "v1"}}
+	b := config.RemoteSource{Git: &latest.GitInfo{Repo: "https://example.com/remote.git", Ref: "v2"}}
+
+	if a.Key() == b.Key() {
+		t.Errorf("expected distinct cache keys for the same repo at different refs, got %s for both", a.Key())
+	}
+}
+
+// TestGetAllConfigsCycleDetection checks that, unless --allow-cyclic-configs is set, getAllConfigs
+// rejects a `requires` graph containing a cycle with a CyclicConfigsError naming every config on
+// the cycle, and that a cycle in one subtree doesn't affect resolution of an unrelated one.
+func TestGetAllConfigsCycleDetection(t *testing.T) {
+	tests := []struct {
+		description  string
+		documents    []document
+		configFilter []string
+		wantErr      string
+	}{
+		{
+			description: "self-loop",
+			documents: []document{
+				{path: "skaffold.yaml", configs: []mockCfg{{name: "cfg00", requiresStanza: `
+requires:
+  - configs: [cfg00]
+`}}},
+			},
+			wantErr: "cyclic config dependencies detected; pass --allow-cyclic-configs to resolve anyway:\ncfg00 @ skaffold.yaml -> cfg00 @ skaffold.yaml",
+		},
+		{
+			description: "2-config cycle",
+			documents: []document{
+				{path: "skaffold.yaml", configs: []mockCfg{{name: "cfg00", requiresStanza: `
+requires:
+  - path: doc1
+    configs: [cfg10]
+`}}},
+				{path: "doc1/skaffold.yaml", configs: []mockCfg{{name: "cfg10", requiresStanza: `
+requires:
+  - path: ../
+    configs: [cfg00]
+`}}},
+			},
+			wantErr: "cyclic config dependencies detected; pass --allow-cyclic-configs to resolve anyway:\ncfg00 @ skaffold.yaml -> cfg10 @ doc1/skaffold.yaml -> cfg00 @ skaffold.yaml",
+		},
+		{
+			description: "3-config cycle",
+			documents: []document{
+				{path: "skaffold.yaml", configs: []mockCfg{{name: "cfg00", requiresStanza: `
+requires:
+  - path: doc1
+    configs: [cfg10]
+`}}},
+				{path: "doc1/skaffold.yaml", configs: []mockCfg{{name: "cfg10", requiresStanza: `
+requires:
+  - path: ../doc2
+    configs: [cfg21]
+`}}},
+				{path: "doc2/skaffold.yaml", configs: []mockCfg{{name: "cfg21", requiresStanza: `
+requires:
+  - path: ../
+    configs: [cfg00]
+`}}},
+			},
+			wantErr: "cyclic config dependencies detected; pass --allow-cyclic-configs to resolve anyway:\ncfg00 @ skaffold.yaml -> cfg10 @ doc1/skaffold.yaml -> cfg21 @ doc2/skaffold.yaml -> cfg00 @ skaffold.yaml",
+		},
+		{
+			description:  "cycle in a sibling subtree does not prevent resolving an unrelated config",
+			configFilter: []string{"cfg01"},
+			documents: []document{
+				{path: "skaffold.yaml", configs: []mockCfg{{name: "cfg00", requiresStanza: `
+requires:
+  - configs: [cfg00]
+`}, {name: "cfg01", requiresStanza: ""}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			tmpDir := t.NewTempDir()
+			for i, d := range test.documents {
+				var cfgs []string
+				for j, c := range d.configs {
+					id := fmt.Sprintf("%d%d", i, j)
+					s := fmt.Sprintf(template, c.name, c.requiresStanza, id, id, id)
+					cfgs = append(cfgs, s)
+				}
+				tmpDir.Write(d.path, strings.Join(cfgs, "\n---\n"))
+			}
+			tmpDir.Chdir()
+
+			_, err := getAllConfigs(config.SkaffoldOptions{
+				Command:             "dev",
+				ConfigurationFile:   test.documents[0].path,
+				ConfigurationFilter: test.configFilter,
+			})
+
+			if test.wantErr == "" {
+				t.CheckNoError(err)
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error %q, got none", test.wantErr)
+			}
+			t.CheckDeepEqual(test.wantErr, err.Error())
+		})
+	}
+}
+
+// TestTransformerSpecs checks that transformerSpecs collects opts.Transformers first, then each
+// resolved config's own `transformers:` stanza in the order the configs appear in out -- in
+// particular, that a config excluded by --module/--profile resolution (and so absent from out)
+// does not contribute its transformers, since transformerSpecs only ever sees the already-filtered
+// list getAllConfigs produces.
+func TestTransformerSpecs(t *testing.T) {
+	global := latest.Transformer{Image: "registry.example.com/global"}
+	cfg00Transformer := latest.Transformer{Image: "registry.example.com/cfg00"}
+	cfg01Transformer := latest.Transformer{Image: "registry.example.com/cfg01"}
+
+	out := []*latest.SkaffoldConfig{
+		{Metadata: latest.Metadata{Name: "cfg00"}, Transformers: []latest.Transformer{cfg00Transformer}},
+		{Metadata: latest.Metadata{Name: "cfg01"}, Transformers: []latest.Transformer{cfg01Transformer}},
+	}
+
+	specs := transformerSpecs(config.SkaffoldOptions{Transformers: []latest.Transformer{global}}, out)
+
+	expected := []latest.Transformer{global, cfg00Transformer, cfg01Transformer}
+	if diff := cmp.Diff(expected, specs); diff != "" {
+		t.Errorf("transformerSpecs mismatch (-want +got):\n%s", diff)
+	}
+
+	// cfg01 is excluded by a --module selection, so its transformer must not be collected.
+	filtered := transformerSpecs(config.SkaffoldOptions{Transformers: []latest.Transformer{global}}, out[:1])
+	expectedFiltered := []latest.Transformer{global, cfg00Transformer}
+	if diff := cmp.Diff(expectedFiltered, filtered); diff != "" {
+		t.Errorf("transformerSpecs mismatch after filtering (-want +got):\n%s", diff)
+	}
+}
+
+// TestLocalizeToDocDir checks that a relative `transformers:` configPath in a non-root document is
+// localized the same way artifact workspaces and `requires` paths are, so it's resolved relative
+// to the document that declared it rather than to the skaffold process's working directory.
+func TestLocalizeToDocDir(t *testing.T) {
+	cfg := &latest.SkaffoldConfig{
+		Transformers: []latest.Transformer{
+			{Image: "registry.example.com/fn", ConfigPath: "fn-config.yaml"},
+			{Image: "registry.example.com/fn-no-config"},
+		},
+	}
+
+	localizeToDocDir(cfg, "/abs/doc/dir")
+
+	expected := []latest.Transformer{
+		{Image: "registry.example.com/fn", ConfigPath: filepath.Join("/abs/doc/dir", "fn-config.yaml")},
+		{Image: "registry.example.com/fn-no-config"},
+	}
+	if diff := cmp.Diff(expected, cfg.Transformers); diff != "" {
+		t.Errorf("localizeToDocDir mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestGetAllConfigsRejectsAmbiguousDependencySource checks that a `requires` entry setting more
+// than one of path/git/oci is rejected, rather than silently resolved via GitRepo's precedence
+// over OCIRepo over Path.
+func TestGetAllConfigsRejectsAmbiguousDependencySource(t *testing.T) {
+	testutil.Run(t, "path and git both set", func(t *testutil.T) {
+		tmpDir := t.NewTempDir()
+		tmpDir.Write("skaffold.yaml", fmt.Sprintf(template, "cfg00", `
+requires:
+  - path: doc1
+    git:
+      repo: https://example.com/remote.git
+    configs: [cfg10]
+`, "00", "00", "00"))
+		tmpDir.Chdir()
+
+		_, err := getAllConfigs(config.SkaffoldOptions{
+			Command:           "dev",
+			ConfigurationFile: "skaffold.yaml",
+		})
+
+		if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+			t.Fatalf("expected a mutually-exclusive-source error, got %v", err)
+		}
+	})
+}
+
 func errorsComparer(a, b error) bool {
 	if a == nil && b == nil {
 		return true
@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+// SkaffoldOptions are options that are set by command line arguments not included
+// in the `skaffold.yaml` config itself.
+type SkaffoldOptions struct {
+	// Command is the skaffold command that is being run.
+	Command string
+
+	// ConfigurationFile is the location of the skaffold config file.
+	ConfigurationFile string
+
+	// ConfigurationFilter is a list of names of configs to select from the configuration file.
+	ConfigurationFilter []string
+
+	// Profiles is the list of profiles to activate.
+	Profiles []string
+
+	// Cache fetches remote `requires` config dependencies (git repositories, OCI artifacts) into
+	// a local directory. When nil, getAllConfigs falls back to a FileCache rooted in the user's
+	// temp directory.
+	Cache DependencyCache
+
+	// AllowCyclicConfigs disables cycle detection across the `requires` graph, restoring the
+	// historical, tolerant behavior of silently resolving each config only once. When false (the
+	// default), a cycle in the `requires` graph is reported as an error instead of being resolved.
+	AllowCyclicConfigs bool
+
+	// Transformers are applied to the fully-resolved list of configs in addition to any
+	// `transformers:` declared by the configs themselves, running first.
+	Transformers []latest.Transformer
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+// RemoteSource describes a remote location that a `requires` config dependency can be fetched from.
+// Exactly one of Git or OCI is set.
+type RemoteSource struct {
+	Git *latest.GitInfo
+	OCI *latest.OCIInfo
+}
+
+// Key returns a stable identifier for this source, suitable for use as a cache directory name.
+// It's derived from the source's address and ref/tag, so the same repository checked out at two
+// different refs gets two distinct cache entries.
+func (s RemoteSource) Key() string {
+	var raw string
+	switch {
+	case s.Git != nil:
+		raw = fmt.Sprintf("git|%s|%s", s.Git.Repo, s.Git.Ref)
+	case s.OCI != nil:
+		raw = fmt.Sprintf("oci|%s", s.OCI.Image)
+	}
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// DependencyCache fetches remote configuration dependencies (git repositories or OCI artifacts)
+// into a local directory so they can be parsed exactly like any other `skaffold.yaml`.
+//
+// Implementations must be safe to share across a single `getAllConfigs` resolution, since the same
+// source may be required by more than one config, and are expected to be idempotent: fetching the
+// same source twice should reuse the cached directory rather than re-fetching.
+type DependencyCache interface {
+	// Fetch retrieves source into a stable local directory, keyed by the source's address and ref,
+	// and returns the path to that directory.
+	Fetch(ctx context.Context, source RemoteSource) (string, error)
+}
+
+// FileCache is the default DependencyCache. It stores fetched sources under a single root
+// directory, one subdirectory per RemoteSource.Key(), and reuses a previous fetch if the
+// destination directory already exists.
+type FileCache struct {
+	// Root is the directory under which remote sources are cached.
+	Root string
+}
+
+// NewFileCache creates a FileCache rooted at the given directory, creating it if necessary.
+func NewFileCache(root string) (*FileCache, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("creating dependency cache directory %q: %w", root, err)
+	}
+	return &FileCache{Root: root}, nil
+}
+
+func (c *FileCache) Fetch(ctx context.Context, source RemoteSource) (string, error) {
+	dir := filepath.Join(c.Root, source.Key())
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+
+	switch {
+	case source.Git != nil:
+		if err := c.fetchGit(ctx, source.Git, dir); err != nil {
+			return "", err
+		}
+	case source.OCI != nil:
+		if err := c.fetchOCI(ctx, source.OCI, dir); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("remote source has neither git nor oci set")
+	}
+
+	return dir, nil
+}
+
+func (c *FileCache) fetchGit(ctx context.Context, g *latest.GitInfo, dir string) error {
+	ref := g.Ref
+	if ref == "" {
+		ref = "master"
+	}
+
+	repo, err := authenticatedGitURL(g.Repo, g.Auth)
+	if err != nil {
+		return fmt.Errorf("applying auth to %s: %w", g.Repo, err)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--branch", ref, repo, dir)
+	if g.Auth != nil && g.Auth.SSHKeyFilePath != "" {
+		cmd.Env = append(os.Environ(), fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %q -o IdentitiesOnly=yes", g.Auth.SSHKeyFilePath))
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("cloning %s@%s: %w\n%s", g.Repo, ref, err, out)
+	}
+	return nil
+}
+
+// authenticatedGitURL returns repo with auth's credentials embedded as userinfo, so that `git
+// clone` authenticates without any ambient credential helper. A bearer Token is sent as the
+// username with no password, matching the convention most git hosts (GitHub, GitLab,
+// Bitbucket) use for HTTPS personal access tokens. SSH key auth is handled separately, via
+// GIT_SSH_COMMAND, since it doesn't fit in the URL. auth may be nil, in which case repo is
+// returned unchanged.
+func authenticatedGitURL(repo string, auth *latest.AuthConfig) (string, error) {
+	if auth == nil || (auth.Token == "" && auth.Username == "" && auth.Password == "") {
+		return repo, nil
+	}
+
+	u, err := url.Parse(repo)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case auth.Token != "":
+		u.User = url.User(auth.Token)
+	case auth.Username != "" || auth.Password != "":
+		u.User = url.UserPassword(auth.Username, auth.Password)
+	}
+	return u.String(), nil
+}
+
+func (c *FileCache) fetchOCI(ctx context.Context, o *latest.OCIInfo, dir string) error {
+	ref := o.Image
+	if o.Checksum != "" {
+		digest, err := c.resolveOCIDigest(ctx, o.Image, o.Auth)
+		if err != nil {
+			return err
+		}
+		if digest != o.Checksum {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", o.Image, o.Checksum, digest)
+		}
+		// Pull by the verified digest, not the tag, so nothing can serve different content
+		// between the manifest check above and the pull below.
+		ref = pinToDigest(o.Image, digest)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	if err := orasPull(ctx, ref, dir, o.Auth); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	return nil
+}
+
+// pinToDigest replaces any tag or digest already on image's final path segment with @digest, so
+// the result addresses content by digest regardless of what the caller originally passed.
+func pinToDigest(image, digest string) string {
+	prefix, name := "", image
+	if i := strings.LastIndexByte(image, '/'); i >= 0 {
+		prefix, name = image[:i+1], image[i+1:]
+	}
+	if i := strings.IndexByte(name, '@'); i >= 0 {
+		name = name[:i]
+	} else if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[:i]
+	}
+	return prefix + name + "@" + digest
+}
+
+// ociAuthArgs returns the `oras` flags needed to authenticate as auth describes, or nil if auth
+// is nil or empty. A bearer Token is passed as a username with no password, matching how most
+// registries (ghcr.io, Docker Hub) accept a personal access token over basic auth.
+func ociAuthArgs(auth *latest.AuthConfig) []string {
+	if auth == nil || (auth.Token == "" && auth.Username == "" && auth.Password == "") {
+		return nil
+	}
+
+	if auth.Token != "" {
+		return []string{"--username", auth.Token, "--password", ""}
+	}
+	return []string{"--username", auth.Username, "--password", auth.Password}
+}
+
+// resolveOCIDigest fetches image's manifest and returns its content digest, so callers can pin a
+// subsequent pull to exactly the manifest they inspected.
+func (c *FileCache) resolveOCIDigest(ctx context.Context, image string, auth *latest.AuthConfig) (string, error) {
+	manifest, err := orasManifestFetch(ctx, image, auth)
+	if err != nil {
+		return "", err
+	}
+	return manifestDigest(manifest), nil
+}
+
+// orasManifestFetch and orasPull wrap the `oras` invocations fetchOCI needs. They're package-level
+// variables, rather than direct exec.CommandContext calls, so tests can substitute a fake `oras`
+// without shelling out to the real binary.
+var (
+	orasManifestFetch = runOrasManifestFetch
+	orasPull          = runOrasPull
+)
+
+func runOrasManifestFetch(ctx context.Context, image string, auth *latest.AuthConfig) ([]byte, error) {
+	args := append([]string{"manifest", "fetch", image}, ociAuthArgs(auth)...)
+	cmd := exec.CommandContext(ctx, "oras", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("fetching manifest for %s: %w", image, err)
+	}
+	return out, nil
+}
+
+func runOrasPull(ctx context.Context, ref, dir string, auth *latest.AuthConfig) error {
+	args := append([]string{"pull", ref, "--output", dir}, ociAuthArgs(auth)...)
+	cmd := exec.CommandContext(ctx, "oras", args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pulling %s: %w\n%s", ref, err, out)
+	}
+	return nil
+}
+
+// manifestDigest returns the content-addressed digest of an OCI manifest, in the usual
+// `sha256:<hex>` form used by image references and registries.
+func manifestDigest(manifest []byte) string {
+	sum := sha256.Sum256(manifest)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
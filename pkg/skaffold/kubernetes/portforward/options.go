@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"fmt"
+	"strings"
+)
+
+// allowedOptionFlags is the allowlist of `kubectl port-forward` flags a PortForwardResource's
+// `options:` may pass through. Flags that would let `options:` override which resource or ports
+// this port-forward targets -- `--namespace`/`-n`, `--pod`, or a second positional
+// resource/ports argument -- are deliberately excluded: the resource and ports always come from
+// the rest of the PortForwardResource entry, never from `options:`.
+var allowedOptionFlags = map[string]bool{
+	"--address":             true,
+	"--pod-running-timeout": true,
+	"--kubeconfig":          true,
+	"--context":             true,
+	"-v":                    true,
+	"--v":                   true,
+}
+
+// ParseOptions splits raw, a POSIX-shell-style argument list (e.g. `--address 0.0.0.0
+// --pod-running-timeout=2m`), into individual args and rejects any flag not in
+// allowedOptionFlags. An empty or all-whitespace raw parses to a nil slice.
+func ParseOptions(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	args, err := splitShellArgs(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing options %q: %w", raw, err)
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			return nil, fmt.Errorf("options %q: unexpected positional argument %q; only flags are allowed, since the target resource and ports always come from the rest of the PortForwardResource entry, never from options", raw, arg)
+		}
+
+		flag := arg
+		hasInlineValue := false
+		if j := strings.Index(flag, "="); j != -1 {
+			flag = flag[:j]
+			hasInlineValue = true
+		}
+		if !allowedOptionFlags[flag] {
+			return nil, fmt.Errorf("options %q: flag %s is not allowed, since it would override how this port-forward selects its target resource or ports", raw, flag)
+		}
+
+		// Every allowed flag takes a value, either inline (`--flag=value`) or as the next
+		// argument; skip over a space-separated value without further validation, since any
+		// string is a valid value for these flags.
+		if !hasInlineValue && i+1 < len(args) {
+			i++
+		}
+	}
+	return args, nil
+}
+
+// splitShellArgs splits raw the way a POSIX shell would: unquoted whitespace separates
+// arguments, single and double quotes each group an argument verbatim (double quotes still
+// honor a backslash escaping a `"` or `\`), and a bare backslash escapes the next character. An
+// unterminated quote is an error.
+func splitShellArgs(raw string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+
+	runes := []rune(raw)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; {
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+
+		case c == '\'':
+			inArg = true
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '\''; j++ {
+				cur.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated '")
+			}
+			i = j
+
+		case c == '"':
+			inArg = true
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) && (runes[j+1] == '"' || runes[j+1] == '\\') {
+					j++
+				}
+				cur.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf(`unterminated "`)
+			}
+			i = j
+
+		case c == '\\' && i+1 < len(runes):
+			inArg = true
+			i++
+			cur.WriteRune(runes[i])
+
+		default:
+			inArg = true
+			cur.WriteRune(c)
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		description string
+		raw         string
+		expected    []string
+		wantErr     string
+	}{
+		{
+			description: "empty options",
+			raw:         "",
+			expected:    nil,
+		},
+		{
+			description: "whitespace-only options",
+			raw:         "   ",
+			expected:    nil,
+		},
+		{
+			description: "single flag with value",
+			raw:         "--address 0.0.0.0",
+			expected:    []string{"--address", "0.0.0.0"},
+		},
+		{
+			description: "flag=value form",
+			raw:         "--pod-running-timeout=2m",
+			expected:    []string{"--pod-running-timeout=2m"},
+		},
+		{
+			description: "multiple flags with quoted value",
+			raw:         `--address 0.0.0.0 --kubeconfig "/home/my user/.kube/config"`,
+			expected:    []string{"--address", "0.0.0.0", "--kubeconfig", "/home/my user/.kube/config"},
+		},
+		{
+			description: "disallowed flag overriding namespace is rejected",
+			raw:         "--namespace other-ns",
+			wantErr:     "flag --namespace is not allowed",
+		},
+		{
+			description: "disallowed flag overriding pod selector is rejected",
+			raw:         "--pod some-other-pod",
+			wantErr:     "flag --pod is not allowed",
+		},
+		{
+			description: "short namespace flag is rejected",
+			raw:         "-n other-ns",
+			wantErr:     "flag -n is not allowed",
+		},
+		{
+			description: "extra positional port mapping is rejected",
+			raw:         "--address 0.0.0.0 9999:80",
+			wantErr:     `unexpected positional argument "9999:80"`,
+		},
+		{
+			description: "unterminated quote is an error",
+			raw:         `--address "0.0.0.0`,
+			wantErr:     `unterminated "`,
+		},
+	}
+
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			args, err := ParseOptions(test.raw)
+
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+
+			t.CheckNoError(err)
+			if diff := cmp.Diff(test.expected, args); diff != "" {
+				t.Errorf("ParseOptions(%q) mismatch (-want +got):\n%s", test.raw, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"sync"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+// portForwardEvent is called whenever a port-forward entry starts. It's a package variable so
+// tests can stub it out instead of needing a real `kubectl port-forward` process.
+var portForwardEvent = func(entry *portForwardEntry) {}
+
+// portForwardEntry is a single active (or about to become active) port-forward, keyed by the
+// resource and container it targets.
+type portForwardEntry struct {
+	resource      latest.PortForwardResource
+	containerName string
+	localPort     int
+
+	// options is resource.Options, already split and validated by ParseOptions.
+	options []string
+
+	cancel context.CancelFunc
+}
+
+// key uniquely identifies the resource+container this entry forwards, independent of localPort.
+func (e *portForwardEntry) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", e.resource.Namespace, e.resource.Type, e.resource.Name, e.containerName)
+}
+
+// portForwardArgs builds the `kubectl port-forward` argument list for entry, including its
+// (already validated) options. It's kept pure and separate from forwardPortForwardEntry so it can
+// be unit tested without actually exec'ing kubectl.
+func portForwardArgs(entry *portForwardEntry) []string {
+	var args []string
+	if entry.resource.Namespace != "" {
+		args = append(args, "--namespace", entry.resource.Namespace)
+	}
+	args = append(args, fmt.Sprintf("%s/%s", entry.resource.Type, entry.resource.Name))
+	args = append(args, entry.options...)
+	args = append(args, fmt.Sprintf("%d:%d", entry.localPort, entry.resource.Port))
+	return append([]string{"port-forward"}, args...)
+}
+
+// EntryManager tracks every active port-forward started by `skaffold dev` and the local ports they
+// hold, so that an automatically chosen local port never collides with one already forwarded and
+// Stop can tear every entry down. It does not look up an existing entry for the same resource: a
+// second newPortForwardEntry call for it picks its local port afresh.
+type EntryManager struct {
+	out            io.Writer
+	forwardedPorts *sync.Map // localPort (int) -> struct{}, every port currently bound
+	entries        *sync.Map // key() -> *portForwardEntry, every entry started so far
+}
+
+// NewEntryManager creates an EntryManager that sends every port-forward's `kubectl` output to out.
+func NewEntryManager(out io.Writer) *EntryManager {
+	return &EntryManager{
+		out:            out,
+		forwardedPorts: &sync.Map{},
+		entries:        &sync.Map{},
+	}
+}
+
+// newPortForwardEntry builds a portForwardEntry for resource, parsing and validating
+// resource.Options via ParseOptions and picking a local port: resource.LocalPort if the user set
+// one, otherwise the first available port starting at resource.Port.
+func (em *EntryManager) newPortForwardEntry(resource latest.PortForwardResource, containerName string) (*portForwardEntry, error) {
+	options, err := ParseOptions(resource.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	localPort := resource.LocalPort
+	if localPort == 0 {
+		localPort = retrieveAvailablePort(resource.Port, em.forwardedPorts)
+	}
+
+	return &portForwardEntry{
+		resource:      resource,
+		containerName: containerName,
+		localPort:     localPort,
+		options:       options,
+	}, nil
+}
+
+// Stop terminates every port-forward this EntryManager started.
+func (em *EntryManager) Stop() {
+	em.entries.Range(func(_, v interface{}) bool {
+		entry := v.(*portForwardEntry)
+		if entry.cancel != nil {
+			entry.cancel()
+		}
+		em.forwardedPorts.Delete(entry.localPort)
+		return true
+	})
+	em.entries = &sync.Map{}
+}
+
+// forwardPortForwardEntry starts `kubectl port-forward` for entry and records it so Stop can tear
+// it down later.
+func (em *EntryManager) forwardPortForwardEntry(ctx context.Context, entry *portForwardEntry) error {
+	ctx, cancel := context.WithCancel(ctx)
+	entry.cancel = cancel
+
+	cmd := exec.CommandContext(ctx, "kubectl", portForwardArgs(entry)...)
+	cmd.Stdout = em.out
+	cmd.Stderr = em.out
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return fmt.Errorf("port forwarding %s: %w", entry.key(), err)
+	}
+
+	em.forwardedPorts.Store(entry.localPort, struct{}{})
+	em.entries.Store(entry.key(), entry)
+	portForwardEvent(entry)
+
+	go func() {
+		_ = cmd.Wait()
+	}()
+	return nil
+}
+
+// retrieveAvailablePort returns the first port, starting at desiredPort, that isn't already
+// tracked in forwardedPorts and can be bound locally.
+func retrieveAvailablePort(desiredPort int, forwardedPorts *sync.Map) int {
+	for port := desiredPort; ; port++ {
+		if _, ok := forwardedPorts.Load(port); ok {
+			continue
+		}
+		l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+		if err != nil {
+			continue
+		}
+		l.Close()
+		return port
+	}
+}
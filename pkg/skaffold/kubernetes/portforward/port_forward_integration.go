@@ -17,15 +17,16 @@ func WhiteBox_PortForwardCycle(namespace string, t *testing.T) {
 	portForwardEvent = func(entry *portForwardEntry) {}
 	ctx := context.Background()
 	localPort := retrieveAvailablePort(9000, em.forwardedPorts)
-	pfe := &portForwardEntry{
-		resource: latest.PortForwardResource{
-			Type:      "deployment",
-			Name:      "leeroy-web",
-			Namespace: namespace,
-			Port:      8080,
-		},
-		containerName: "dummy container",
-		localPort:     localPort,
+	pfe, err := em.newPortForwardEntry(latest.PortForwardResource{
+		Type:      "deployment",
+		Name:      "leeroy-web",
+		Namespace: namespace,
+		Port:      8080,
+		LocalPort: localPort,
+		Options:   "--address 0.0.0.0",
+	}, "dummy container")
+	if err != nil {
+		t.Fatalf("failed to build port forward entry: %s", err)
 	}
 
 	defer em.Stop()
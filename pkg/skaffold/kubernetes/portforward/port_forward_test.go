@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestNewPortForwardEntryRejectsUnsafeOptions(t *testing.T) {
+	em := NewEntryManager(nil)
+
+	_, err := em.newPortForwardEntry(latest.PortForwardResource{
+		Type:      "deployment",
+		Name:      "leeroy-web",
+		Port:      8080,
+		LocalPort: 9000,
+		// attempts to override the pod selector kubectl port-forward is invoked with
+		Options: "--pod some-other-pod",
+	}, "web")
+
+	if err == nil {
+		t.Fatalf("expected an error for an unsafe options flag, got none")
+	}
+}
+
+func TestNewPortForwardEntryUsesLocalPortWhenSet(t *testing.T) {
+	em := NewEntryManager(nil)
+
+	entry, err := em.newPortForwardEntry(latest.PortForwardResource{
+		Type: "deployment", Name: "leeroy-web", Port: 8080, LocalPort: 9123,
+	}, "web")
+
+	if err != nil {
+		t.Fatalf("newPortForwardEntry: %v", err)
+	}
+	if entry.localPort != 9123 {
+		t.Errorf("expected localPort 9123 (from resource.LocalPort), got %d", entry.localPort)
+	}
+}
+
+func TestPortForwardArgs(t *testing.T) {
+	tests := []struct {
+		description string
+		resource    latest.PortForwardResource
+		expected    []string
+	}{
+		{
+			description: "no namespace, no options",
+			resource:    latest.PortForwardResource{Type: "deployment", Name: "leeroy-web", Port: 8080, LocalPort: 9000},
+			expected:    []string{"port-forward", "deployment/leeroy-web", "9000:8080"},
+		},
+		{
+			description: "namespace set",
+			resource:    latest.PortForwardResource{Type: "deployment", Name: "leeroy-web", Namespace: "ns", Port: 8080, LocalPort: 9000},
+			expected:    []string{"port-forward", "--namespace", "ns", "deployment/leeroy-web", "9000:8080"},
+		},
+		{
+			description: "options are parsed and land between the resource and the port mapping",
+			resource:    latest.PortForwardResource{Type: "deployment", Name: "leeroy-web", Port: 8080, LocalPort: 9000, Options: "--address 0.0.0.0 --pod-running-timeout=2m"},
+			expected:    []string{"port-forward", "deployment/leeroy-web", "--address", "0.0.0.0", "--pod-running-timeout=2m", "9000:8080"},
+		},
+	}
+
+	em := NewEntryManager(nil)
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			entry, err := em.newPortForwardEntry(test.resource, "web")
+			t.CheckNoError(err)
+
+			args := portForwardArgs(entry)
+			if diff := cmp.Diff(test.expected, args); diff != "" {
+				t.Errorf("portForwardArgs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
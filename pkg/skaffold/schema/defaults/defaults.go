@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package defaults fills in the default values for fields a user left unset in a `skaffold.yaml`.
+package defaults
+
+import (
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+const (
+	defaultDockerfilePath = "Dockerfile"
+	defaultLogPrefix      = "container"
+	defaultConcurrency    = 1
+)
+
+// Set applies the documented default values to cfg: artifacts with no workspace default to `.`,
+// artifacts with no builder default to a Dockerfile-based docker build, an unset tag policy defaults to the git tagger, an unset build
+// type defaults to a local build, and deployed containers default to logging with a `container`
+// prefix. Because a profile's Build/Deploy overrides entirely replace the base config's once
+// activated, defaults are also applied to every profile so an activated profile is defaulted
+// independently of whatever it's overriding.
+func Set(cfg *latest.SkaffoldConfig) error {
+	setPipelineDefaults(&cfg.Pipeline)
+	for i := range cfg.Profiles {
+		setPipelineDefaults(&cfg.Profiles[i].Pipeline)
+	}
+	return nil
+}
+
+func setPipelineDefaults(p *latest.Pipeline) {
+	for _, a := range p.Build.Artifacts {
+		setArtifactType(a)
+	}
+	setTagPolicy(&p.Build.TagPolicy)
+	setBuildType(&p.Build.BuildType)
+	setLogsConfig(&p.Deploy.Logs)
+}
+
+func setArtifactType(a *latest.Artifact) {
+	if a.Workspace == "" {
+		a.Workspace = "."
+	}
+	if a.ArtifactType.DockerArtifact == nil {
+		a.ArtifactType.DockerArtifact = &latest.DockerArtifact{DockerfilePath: defaultDockerfilePath}
+	}
+}
+
+func setTagPolicy(t *latest.TagPolicy) {
+	if t.GitTagger == nil {
+		t.GitTagger = &latest.GitTagger{}
+	}
+}
+
+func setBuildType(b *latest.BuildType) {
+	if b.LocalBuild == nil {
+		concurrency := defaultConcurrency
+		b.LocalBuild = &latest.LocalBuild{Concurrency: &concurrency}
+	}
+}
+
+func setLogsConfig(l *latest.LogsConfig) {
+	if l.Prefix == "" {
+		l.Prefix = defaultLogPrefix
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package latest
+
+// SkaffoldConfig is the top level config object that is parsed from a `skaffold.yaml`.
+type SkaffoldConfig struct {
+	// APIVersion is the version of the configuration.
+	APIVersion string `yaml:"apiVersion" yamltags:"required"`
+
+	// Kind is always `Config`.
+	Kind string `yaml:"kind" yamltags:"required"`
+
+	// Metadata holds additional information about the config.
+	Metadata Metadata `yaml:"metadata,omitempty"`
+
+	// Dependencies are other `skaffold.yaml` configs that this config depends on.
+	Dependencies []ConfigDependency `yaml:"requires,omitempty"`
+
+	Pipeline `yaml:",inline"`
+
+	// Profiles can override everything.
+	Profiles []Profile `yaml:"profiles,omitempty"`
+
+	// Transformers are KRM-function-style containers that mutate the fully-resolved list of
+	// configs (this one and every other config `getAllConfigs` produced) before it reaches the
+	// build/deploy pipeline.
+	Transformers []Transformer `yaml:"transformers,omitempty"`
+}
+
+// Transformer describes a container, following the KRM function contract, that receives the
+// fully-resolved list of configs as a `ResourceList` on stdin and replaces it with whatever
+// `ResourceList` it writes to stdout.
+type Transformer struct {
+	// Image is the container image that implements this transformer.
+	Image string `yaml:"image" yamltags:"required"`
+
+	// ConfigPath is the path to a file parsed and passed to the container as the ResourceList's
+	// `functionConfig`, the KRM function's own configuration.
+	ConfigPath string `yaml:"configPath,omitempty"`
+
+	// Network is the container network the transformer is run with, e.g. `none` to deny network
+	// access. Defaults to the container runtime's default network.
+	Network string `yaml:"network,omitempty"`
+
+	// Mounts are extra volumes mounted into the transformer container, in `docker run -v` syntax.
+	Mounts []string `yaml:"mounts,omitempty"`
+
+	// Env are extra environment variables set in the transformer container, in `NAME=value` syntax.
+	Env []string `yaml:"env,omitempty"`
+}
+
+// Metadata holds an optional name of the project.
+type Metadata struct {
+	// Name is an identifier for the project.
+	Name string `yaml:"name,omitempty"`
+}
+
+// Pipeline describes a Skaffold pipeline.
+type Pipeline struct {
+	Build       BuildConfig            `yaml:"build,omitempty"`
+	Deploy      DeployConfig           `yaml:"deploy,omitempty"`
+	PortForward []*PortForwardResource `yaml:"portForward,omitempty"`
+}
+
+// BuildConfig contains all the configuration for the build steps.
+type BuildConfig struct {
+	Artifacts []*Artifact `yaml:"artifacts,omitempty"`
+	TagPolicy TagPolicy   `yaml:"tagPolicy,omitempty"`
+	BuildType `yaml:",inline"`
+}
+
+// DeployConfig contains all the configuration needed by the deploy steps.
+type DeployConfig struct {
+	Logs LogsConfig `yaml:"logs,omitempty"`
+}
+
+// LogsConfig configures how container logs are printed as a result of a deployment.
+type LogsConfig struct {
+	// Prefix defines the prefix shown on each log line.
+	Prefix string `yaml:"prefix,omitempty"`
+}
+
+// PortForwardResource describes a resource to port forward during `skaffold dev`.
+type PortForwardResource struct {
+	// Type is the resource type to port forward, e.g. `deployment`, `pod`, `service`.
+	Type string `yaml:"resourceType,omitempty" yamltags:"required"`
+
+	// Name is the name of the resource to port forward.
+	Name string `yaml:"resourceName,omitempty" yamltags:"required"`
+
+	// Namespace is the namespace the resource lives in. Defaults to the current namespace.
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// Port is the resource port to forward from.
+	Port int `yaml:"port,omitempty" yamltags:"required"`
+
+	// LocalPort is the local port to bind to. If empty, an available port is chosen automatically.
+	LocalPort int `yaml:"localPort,omitempty"`
+
+	// Options is a shell-style argument list passed to the underlying `kubectl port-forward`
+	// invocation between the resource and the port mapping, e.g. `--address 0.0.0.0 --pod-running-timeout=2m`. Only a small allowlist of
+	// flags is accepted; see `portforward.ParseOptions`.
+	Options string `yaml:"options,omitempty"`
+}
+
+// Artifact are the items that need to be built, along with the context in which they should be built.
+type Artifact struct {
+	// ImageName is the name of the image to be built.
+	ImageName string `yaml:"image,omitempty"`
+
+	// Workspace is the directory containing the artifact's sources.
+	Workspace string `yaml:"context,omitempty"`
+
+	ArtifactType `yaml:",inline"`
+}
+
+// ArtifactType describes the builder used to construct the artifact's image.
+type ArtifactType struct {
+	// DockerArtifact describes an artifact built from a Dockerfile.
+	DockerArtifact *DockerArtifact `yaml:"docker,omitempty"`
+}
+
+// DockerArtifact describes an artifact built from a Dockerfile, usually using `docker build`.
+type DockerArtifact struct {
+	// DockerfilePath is the path to the Dockerfile, relative to the workspace.
+	DockerfilePath string `yaml:"dockerfile,omitempty"`
+}
+
+// TagPolicy contains all the configuration for the tagging step.
+type TagPolicy struct {
+	// GitTagger tags images with the git tag or commit hash of the artifact's workspace.
+	GitTagger *GitTagger `yaml:"gitCommit,omitempty"`
+}
+
+// GitTagger contains the configuration for the git tagger.
+type GitTagger struct{}
+
+// BuildType contains the specific implementation and parameters needed to build an artifact.
+type BuildType struct {
+	// LocalBuild builds artifacts on the host.
+	LocalBuild *LocalBuild `yaml:"local,omitempty"`
+}
+
+// LocalBuild describes how to do a build on the local docker daemon and doesn't require a cluster.
+type LocalBuild struct {
+	// Concurrency is how many artifacts can be built concurrently.
+	Concurrency *int `yaml:"concurrency,omitempty"`
+}
+
+// Profile is additional configuration that overrides default configuration when it is activated.
+type Profile struct {
+	// Name is a unique profile name.
+	Name string `yaml:"name,omitempty" yamltags:"required"`
+
+	Pipeline `yaml:",inline"`
+}
+
+// ConfigDependency describes a dependency on another `skaffold.yaml` config, either in this
+// same document, in another document in the same repository, or fetched from a remote source.
+type ConfigDependency struct {
+	// Names includes specific named configs within the file path. If empty, then all configs in the file are included.
+	Names []string `yaml:"configs,omitempty"`
+
+	// Path describes the path to the file containing the required configs, relative to `skaffold.yaml`.
+	// It is mutually exclusive with `git` and `oci`.
+	Path string `yaml:"path,omitempty"`
+
+	// GitRepo describes a remote git repository containing the required configs.
+	// It is mutually exclusive with `path` and `oci`.
+	GitRepo *GitInfo `yaml:"git,omitempty"`
+
+	// OCIRepo describes a remote OCI artifact containing the required configs.
+	// It is mutually exclusive with `path` and `git`.
+	OCIRepo *OCIInfo `yaml:"oci,omitempty"`
+
+	// ActiveProfiles describes the list of profiles to activate when this config is included
+	// as a required dependency and the list of profiles that trigger that activation in the parent config.
+	ActiveProfiles []ProfileDependency `yaml:"activeProfiles,omitempty"`
+}
+
+// GitInfo describes a remote git repository containing one or more `skaffold.yaml` configs.
+type GitInfo struct {
+	// Repo is the git repository URL, e.g. `https://github.com/GoogleContainerTools/skaffold`.
+	Repo string `yaml:"repo" yamltags:"required"`
+
+	// Path is the path to the required configs, relative to the root of the git repository. Defaults to `skaffold.yaml`.
+	Path string `yaml:"path,omitempty"`
+
+	// Ref is the git ref the repository should be cloned at, e.g. a branch or tag. Defaults to `master`.
+	// The repository is cloned with `git clone --branch`, so a commit sha is not accepted.
+	Ref string `yaml:"ref,omitempty"`
+
+	// Auth describes the authentication used to access a private repository.
+	Auth *AuthConfig `yaml:"auth,omitempty"`
+}
+
+// OCIInfo describes a remote OCI artifact, pulled from a registry, containing one or more `skaffold.yaml` configs.
+type OCIInfo struct {
+	// Image is the OCI image reference of the skaffold config artifact, e.g. `gcr.io/my-project/my-configs:latest`.
+	Image string `yaml:"image" yamltags:"required"`
+
+	// Path is the path to the required configs within the pulled artifact. Defaults to `skaffold.yaml`.
+	Path string `yaml:"path,omitempty"`
+
+	// Auth describes the authentication used to pull from a private registry.
+	Auth *AuthConfig `yaml:"auth,omitempty"`
+
+	// Checksum is the expected sha256 digest of the pulled artifact's manifest, e.g.
+	// `sha256:e3b0c4...`. Since an image tag is mutable, set this to pin the dependency to a
+	// known manifest and fail the fetch if the registry ever serves something else. Optional.
+	Checksum string `yaml:"checksum,omitempty"`
+}
+
+// AuthConfig describes the authentication used to access a remote config source.
+type AuthConfig struct {
+	// Username is the username used for basic authentication.
+	Username string `yaml:"username,omitempty"`
+
+	// Password is the password used for basic authentication.
+	Password string `yaml:"password,omitempty"`
+
+	// Token is a bearer token, e.g. a personal access token, used instead of basic authentication.
+	Token string `yaml:"token,omitempty"`
+
+	// SSHKeyFilePath is the path to an SSH private key used to authenticate over SSH.
+	SSHKeyFilePath string `yaml:"sshKeyFilePath,omitempty"`
+}
+
+// ProfileDependency describes the relationship between the activation of a profile in the
+// parent config and the activation of a profile in a required dependency.
+type ProfileDependency struct {
+	// Name is the profile name to activate in the dependency config.
+	Name string `yaml:"name,omitempty" yamltags:"required"`
+
+	// ActivatedBy lists the profiles, which when activated in the parent config, will also activate this profile
+	// in the dependency config. If empty, this profile is unconditionally activated.
+	ActivatedBy []string `yaml:"activatedBy,omitempty"`
+}
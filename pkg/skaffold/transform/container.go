@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+// pulledImages tracks, for a single applyTransformers call, which transformer images have already
+// been pulled, so that a transformer image shared by several `transformers:` specs (or required
+// again by a cycle in the config graph) is only pulled once.
+type pulledImages struct {
+	mu     sync.Mutex
+	pulled map[string]bool
+}
+
+func newPulledImages() *pulledImages {
+	return &pulledImages{pulled: make(map[string]bool)}
+}
+
+func (p *pulledImages) ensurePulled(ctx context.Context, image string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pulled[image] {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, "docker", "pull", image)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pulling transformer image %s: %w\n%s", image, err, out)
+	}
+	p.pulled[image] = true
+	return nil
+}
+
+// ContainerTransformer runs a latest.Transformer spec as a container, following the KRM function
+// contract: the resolved configs go in as a ResourceList on stdin, and whatever ResourceList the
+// container writes to stdout becomes the new resolved config list.
+type ContainerTransformer struct {
+	spec   latest.Transformer
+	pulled *pulledImages
+}
+
+// NewContainerTransformer creates a ContainerTransformer for spec. pulled is shared across every
+// transformer invoked by the same Run call, so the same image is pulled at most once.
+func NewContainerTransformer(spec latest.Transformer, pulled *pulledImages) *ContainerTransformer {
+	return &ContainerTransformer{spec: spec, pulled: pulled}
+}
+
+func (t *ContainerTransformer) Transform(ctx context.Context, configs []*latest.SkaffoldConfig) ([]*latest.SkaffoldConfig, error) {
+	in, err := encodeResourceList(t.spec, configs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.pulled.ensurePulled(ctx, t.spec.Image); err != nil {
+		return nil, err
+	}
+
+	args := []string{"run", "--rm", "-i"}
+	if t.spec.Network != "" {
+		args = append(args, "--network", t.spec.Network)
+	}
+	for _, mount := range t.spec.Mounts {
+		args = append(args, "-v", mount)
+	}
+	for _, env := range t.spec.Env {
+		args = append(args, "-e", env)
+	}
+	args = append(args, t.spec.Image)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdin = bytes.NewReader(in)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running transformer %s: %w\n%s", t.spec.Image, err, stderr.String())
+	}
+
+	return decodeResourceList(t.spec, stdout.Bytes())
+}
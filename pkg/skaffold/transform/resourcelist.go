@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+// resourceList is the KRM function wire format: https://github.com/GoogleContainerTools/kpt/blob/main/docs/resource-io-spec.md
+type resourceList struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+
+	// Items is the resolved config list, one SkaffoldConfig document per item.
+	Items []*latest.SkaffoldConfig `yaml:"items"`
+
+	// FunctionConfig is the transformer's own configuration, loaded from its ConfigPath. It is
+	// left out of the ResourceList if the transformer has no ConfigPath.
+	FunctionConfig yaml.MapSlice `yaml:"functionConfig,omitempty"`
+}
+
+// encodeResourceList marshals configs, and the transformer's own config (if it has one), into the
+// KRM ResourceList wire format.
+func encodeResourceList(spec latest.Transformer, configs []*latest.SkaffoldConfig) ([]byte, error) {
+	rl := resourceList{
+		APIVersion: "config.kubernetes.io/v1",
+		Kind:       "ResourceList",
+		Items:      configs,
+	}
+
+	if spec.ConfigPath != "" {
+		raw, err := ioutil.ReadFile(spec.ConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading functionConfig %q for transformer %s: %w", spec.ConfigPath, spec.Image, err)
+		}
+		var fnConfig yaml.MapSlice
+		if err := yaml.Unmarshal(raw, &fnConfig); err != nil {
+			return nil, fmt.Errorf("parsing functionConfig %q for transformer %s: %w", spec.ConfigPath, spec.Image, err)
+		}
+		rl.FunctionConfig = fnConfig
+	}
+
+	buf, err := yaml.Marshal(rl)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling ResourceList for transformer %s: %w", spec.Image, err)
+	}
+	return buf, nil
+}
+
+// decodeResourceList parses the ResourceList a transformer container wrote to stdout, returning
+// its items as the new resolved config list.
+func decodeResourceList(spec latest.Transformer, out []byte) ([]*latest.SkaffoldConfig, error) {
+	var rl resourceList
+	if err := yaml.Unmarshal(bytes.TrimSpace(out), &rl); err != nil {
+		return nil, fmt.Errorf("parsing ResourceList output of transformer %s: %w", spec.Image, err)
+	}
+	return rl.Items, nil
+}
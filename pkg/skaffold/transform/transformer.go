@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package transform applies KRM-function-style containers (see
+// https://kubectl.docs.kubernetes.io/guides/extending_kubectl/binary_plugin/ and kyaml's `runfn`)
+// to the list of *latest.SkaffoldConfig resolved by getAllConfigs, before it reaches the
+// build/deploy pipeline.
+package transform
+
+import (
+	"context"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+// Transformer mutates the fully-resolved list of configs, following the KRM function contract:
+// a ResourceList of items goes in, a (possibly modified) ResourceList of items comes out.
+type Transformer interface {
+	Transform(ctx context.Context, configs []*latest.SkaffoldConfig) ([]*latest.SkaffoldConfig, error)
+}
+
+// FromSpecs builds a Transformer for each spec, in order, sharing a single pulled-image cache so a
+// transformer image used by more than one spec is only pulled once.
+func FromSpecs(specs []latest.Transformer) []Transformer {
+	pulled := newPulledImages()
+	transformers := make([]Transformer, len(specs))
+	for i, spec := range specs {
+		transformers[i] = NewContainerTransformer(spec, pulled)
+	}
+	return transformers
+}
+
+// Run applies every transformer in transformers, in order, threading each one's output into the
+// next. A transformer that fails aborts the whole resolution, since there's no meaningful partial
+// result to fall back to.
+func Run(ctx context.Context, transformers []Transformer, configs []*latest.SkaffoldConfig) ([]*latest.SkaffoldConfig, error) {
+	for _, t := range transformers {
+		transformed, err := t.Transform(ctx, configs)
+		if err != nil {
+			return nil, err
+		}
+		configs = transformed
+	}
+	return configs, nil
+}
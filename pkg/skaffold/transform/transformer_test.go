@@ -0,0 +1,190 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+// addRegistryPrefix is a Transformer that rewrites every artifact's `image:` field to prepend
+// a registry prefix, the way a real "add-registry" KRM function would.
+type addRegistryPrefix struct {
+	prefix string
+}
+
+func (a addRegistryPrefix) Transform(_ context.Context, configs []*latest.SkaffoldConfig) ([]*latest.SkaffoldConfig, error) {
+	for _, cfg := range configs {
+		for _, artifact := range cfg.Build.Artifacts {
+			artifact.ImageName = a.prefix + artifact.ImageName
+		}
+	}
+	return configs, nil
+}
+
+// injectProfile is a Transformer that appends a new profile to every config, the way a real
+// "inject-profile" KRM function would.
+type injectProfile struct {
+	profile latest.Profile
+}
+
+func (i injectProfile) Transform(_ context.Context, configs []*latest.SkaffoldConfig) ([]*latest.SkaffoldConfig, error) {
+	for _, cfg := range configs {
+		cfg.Profiles = append(cfg.Profiles, i.profile)
+	}
+	return configs, nil
+}
+
+// failingTransformer always errors, the way a transformer container exiting non-zero would.
+type failingTransformer struct {
+	err error
+}
+
+func (f failingTransformer) Transform(context.Context, []*latest.SkaffoldConfig) ([]*latest.SkaffoldConfig, error) {
+	return nil, f.err
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		description  string
+		transformers []Transformer
+		configs      []*latest.SkaffoldConfig
+		wantErr      string
+		expected     []*latest.SkaffoldConfig
+	}{
+		{
+			description:  "no transformers is a no-op",
+			transformers: nil,
+			configs:      []*latest.SkaffoldConfig{{Metadata: latest.Metadata{Name: "cfg00"}}},
+			expected:     []*latest.SkaffoldConfig{{Metadata: latest.Metadata{Name: "cfg00"}}},
+		},
+		{
+			description:  "rewrites image names",
+			transformers: []Transformer{addRegistryPrefix{prefix: "registry.example.com/"}},
+			configs: []*latest.SkaffoldConfig{
+				{Pipeline: latest.Pipeline{Build: latest.BuildConfig{Artifacts: []*latest.Artifact{{ImageName: "app"}}}}},
+			},
+			expected: []*latest.SkaffoldConfig{
+				{Pipeline: latest.Pipeline{Build: latest.BuildConfig{Artifacts: []*latest.Artifact{{ImageName: "registry.example.com/app"}}}}},
+			},
+		},
+		{
+			description:  "injects a profile",
+			transformers: []Transformer{injectProfile{profile: latest.Profile{Name: "injected"}}},
+			configs:      []*latest.SkaffoldConfig{{Metadata: latest.Metadata{Name: "cfg00"}}},
+			expected: []*latest.SkaffoldConfig{
+				{Metadata: latest.Metadata{Name: "cfg00"}, Profiles: []latest.Profile{{Name: "injected"}}},
+			},
+		},
+		{
+			description: "transformers run in order, each seeing the prior one's output",
+			transformers: []Transformer{
+				addRegistryPrefix{prefix: "registry.example.com/"},
+				injectProfile{profile: latest.Profile{Name: "injected"}},
+			},
+			configs: []*latest.SkaffoldConfig{
+				{Pipeline: latest.Pipeline{Build: latest.BuildConfig{Artifacts: []*latest.Artifact{{ImageName: "app"}}}}},
+			},
+			expected: []*latest.SkaffoldConfig{
+				{
+					Pipeline: latest.Pipeline{Build: latest.BuildConfig{Artifacts: []*latest.Artifact{{ImageName: "registry.example.com/app"}}}},
+					Profiles: []latest.Profile{{Name: "injected"}},
+				},
+			},
+		},
+		{
+			description: "a failing transformer aborts resolution and later transformers don't run",
+			transformers: []Transformer{
+				injectProfile{profile: latest.Profile{Name: "should-not-apply"}},
+				failingTransformer{err: errors.New("boom")},
+				injectProfile{profile: latest.Profile{Name: "should-not-run-either"}},
+			},
+			configs: []*latest.SkaffoldConfig{{Metadata: latest.Metadata{Name: "cfg00"}}},
+			wantErr: "boom",
+		},
+	}
+
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			got, err := Run(context.Background(), test.transformers, test.configs)
+
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("expected error %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expected, got)
+		})
+	}
+}
+
+func TestFromSpecsSharesPulledImagesCache(t *testing.T) {
+	specs := []latest.Transformer{
+		{Image: "registry.example.com/a"},
+		{Image: "registry.example.com/a"},
+		{Image: "registry.example.com/b"},
+	}
+
+	transformers := FromSpecs(specs)
+	if len(transformers) != len(specs) {
+		t.Fatalf("expected %d transformers, got %d", len(specs), len(transformers))
+	}
+
+	ct0, ok0 := transformers[0].(*ContainerTransformer)
+	ct1, ok1 := transformers[1].(*ContainerTransformer)
+	if !ok0 || !ok1 {
+		t.Fatalf("expected *ContainerTransformer, got %T and %T", transformers[0], transformers[1])
+	}
+	if ct0.pulled != ct1.pulled {
+		t.Errorf("expected transformers built from the same FromSpecs call to share a pulled-image cache")
+	}
+}
+
+func TestEncodeDecodeResourceListRoundTrip(t *testing.T) {
+	spec := latest.Transformer{Image: "registry.example.com/transformer"}
+	configs := []*latest.SkaffoldConfig{
+		{
+			APIVersion: "skaffold/v2beta11",
+			Kind:       "Config",
+			Metadata:   latest.Metadata{Name: "cfg00"},
+			Pipeline:   latest.Pipeline{Build: latest.BuildConfig{Artifacts: []*latest.Artifact{{ImageName: "app"}}}},
+		},
+	}
+
+	encoded, err := encodeResourceList(spec, configs)
+	if err != nil {
+		t.Fatalf("encodeResourceList: %v", err)
+	}
+
+	decoded, err := decodeResourceList(spec, encoded)
+	if err != nil {
+		t.Fatalf("decodeResourceList: %v", err)
+	}
+
+	if diff := cmp.Diff(configs, decoded); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}